@@ -0,0 +1,63 @@
+package eplogger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// CtxKeyvalser is a func that inspects ctx and appends additional key/value
+// pairs into keyvals without removing any existing elements, then returns
+// the extended keyvals. It follows the same append-only contract as
+// AppendKeyvalser, but is sourced from a context.Context rather than a
+// request or response object.
+type CtxKeyvalser func(ctx context.Context, keyvals []interface{}) []interface{}
+
+const (
+	traceIDKey = "trace_id"
+	spanIDKey  = "span_id"
+)
+
+// spanContextIDer is satisfied by opentracing.SpanContext implementations
+// that expose a trace and span ID as fmt.Stringer (e.g. Jaeger's
+// SpanContext), which is the common case in the go-kit ecosystem.
+type spanContextIDer interface {
+	TraceID() fmt.Stringer
+	SpanID() fmt.Stringer
+}
+
+// CtxKeyvalsFromOpentracing returns a CtxKeyvalser that appends trace_id
+// and span_id extracted from the opentracing.Span stored on ctx by
+// go-kit's tracing/opentracing middleware. It is a no-op if ctx carries
+// no span, or the span's SpanContext does not expose IDs.
+func CtxKeyvalsFromOpentracing() CtxKeyvalser {
+	return func(ctx context.Context, keyvals []interface{}) []interface{} {
+		span := opentracing.SpanFromContext(ctx)
+		if span == nil {
+			return keyvals
+		}
+		ids, ok := span.Context().(spanContextIDer)
+		if !ok {
+			return keyvals
+		}
+		return append(keyvals,
+			traceIDKey, ids.TraceID().String(),
+			spanIDKey, ids.SpanID().String())
+	}
+}
+
+// CtxKeyvalsFromKeys returns a CtxKeyvalser that looks up each of the given
+// context keys via ctx.Value and, when present, appends it to keyvals
+// using fmt.Sprintf("%v", key) as the logged key name. Keys not present in
+// ctx are skipped.
+func CtxKeyvalsFromKeys(keys ...interface{}) CtxKeyvalser {
+	return func(ctx context.Context, keyvals []interface{}) []interface{} {
+		for _, k := range keys {
+			if v := ctx.Value(k); v != nil {
+				keyvals = append(keyvals, fmt.Sprintf("%v", k), v)
+			}
+		}
+		return keyvals
+	}
+}
@@ -0,0 +1,109 @@
+package eplogger
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+)
+
+// fakeSpanContext is a minimal opentracing.SpanContext that also
+// implements spanContextIDer, mirroring uber/jaeger-client-go's
+// SpanContext shape (TraceID/SpanID as methods returning fmt.Stringer).
+type fakeSpanContext struct {
+	traceID fakeStringer
+	spanID  fakeStringer
+}
+
+func (c fakeSpanContext) ForeachBaggageItem(handler func(k, v string) bool) {}
+
+func (c fakeSpanContext) TraceID() fmt.Stringer { return c.traceID }
+
+func (c fakeSpanContext) SpanID() fmt.Stringer { return c.spanID }
+
+type fakeStringer string
+
+func (s fakeStringer) String() string { return string(s) }
+
+// plainSpanContext is an opentracing.SpanContext that does NOT implement
+// spanContextIDer, representing tracers (e.g. opentracing-go's
+// mocktracer) whose SpanContext exposes trace/span IDs as plain fields
+// rather than fmt.Stringer-returning methods.
+type plainSpanContext struct {
+	TraceID int
+	SpanID  int
+}
+
+func (c plainSpanContext) ForeachBaggageItem(handler func(k, v string) bool) {}
+
+// fakeSpan is a minimal opentracing.Span wrapping a fixed SpanContext.
+type fakeSpan struct {
+	ctx opentracing.SpanContext
+}
+
+func (s *fakeSpan) Finish()                                                {}
+func (s *fakeSpan) FinishWithOptions(opts opentracing.FinishOptions)       {}
+func (s *fakeSpan) Context() opentracing.SpanContext                       { return s.ctx }
+func (s *fakeSpan) SetOperationName(operationName string) opentracing.Span { return s }
+func (s *fakeSpan) SetTag(key string, value interface{}) opentracing.Span  { return s }
+func (s *fakeSpan) LogFields(fields ...otlog.Field)                        {}
+func (s *fakeSpan) LogKV(alternatingKeyValues ...interface{})              {}
+func (s *fakeSpan) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	return s
+}
+func (s *fakeSpan) BaggageItem(restrictedKey string) string               { return "" }
+func (s *fakeSpan) Tracer() opentracing.Tracer                            { return nil }
+func (s *fakeSpan) LogEvent(event string)                                 {}
+func (s *fakeSpan) LogEventWithPayload(event string, payload interface{}) {}
+func (s *fakeSpan) Log(data opentracing.LogData)                          {}
+
+// TestCtxKeyvalsFromOpentracing tests that CtxKeyvalsFromOpentracing
+// appends trace_id/span_id when the span's SpanContext implements
+// spanContextIDer, and is a no-op when it doesn't or no span is present.
+func TestCtxKeyvalsFromOpentracing(t *testing.T) {
+	extractor := CtxKeyvalsFromOpentracing()
+
+	t.Run("span context implements spanContextIDer", func(t *testing.T) {
+		span := &fakeSpan{ctx: fakeSpanContext{traceID: "trace-1", spanID: "span-1"}}
+		ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+		keyvals := extractor(ctx, []interface{}{"existing", "kv"})
+
+		if want, have := 6, len(keyvals); want != have {
+			t.Fatalf("len of keyvals is different than expected: want %d, have %d", want, have)
+		}
+		if want, have := traceIDKey, keyvals[2]; want != have {
+			t.Errorf("keyvals[2]: want %s, have %s", want, have)
+		}
+		if want, have := "trace-1", keyvals[3]; want != have {
+			t.Errorf("keyvals[3]: want %s, have %s", want, have)
+		}
+		if want, have := spanIDKey, keyvals[4]; want != have {
+			t.Errorf("keyvals[4]: want %s, have %s", want, have)
+		}
+		if want, have := "span-1", keyvals[5]; want != have {
+			t.Errorf("keyvals[5]: want %s, have %s", want, have)
+		}
+	})
+
+	t.Run("span context does not implement spanContextIDer", func(t *testing.T) {
+		span := &fakeSpan{ctx: plainSpanContext{TraceID: 1, SpanID: 2}}
+		ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+		keyvals := extractor(ctx, []interface{}{"existing", "kv"})
+
+		if want, have := 2, len(keyvals); want != have {
+			t.Fatalf("len of keyvals is different than expected: want %d, have %d", want, have)
+		}
+	})
+
+	t.Run("no span on context", func(t *testing.T) {
+		keyvals := extractor(context.Background(), []interface{}{"existing", "kv"})
+
+		if want, have := 2, len(keyvals); want != have {
+			t.Fatalf("len of keyvals is different than expected: want %d, have %d", want, have)
+		}
+	})
+}
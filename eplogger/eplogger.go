@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
+	"github.com/go-logr/logr"
 	"time"
 )
 
@@ -41,15 +42,82 @@ const (
 //
 // The level specified as defaultLevel will be used when the resulting error
 // is nil otherwise level.Error will be used.
+//
+// It is equivalent to calling NewLoggingMiddleware with no options.
 func LoggingMiddleware(logger, errLogger log.Logger) endpoint.Middleware {
+	return NewLoggingMiddleware(logger, errLogger)
+}
+
+// NewLoggingMiddleware is the configurable counterpart to LoggingMiddleware.
+// With no opts it behaves identically to LoggingMiddleware. Use
+// WithContextKeyvals to pull additional key/value pairs out of the
+// request's context.Context (e.g. request_id, trace_id, tenant, user)
+// before logging.
+func NewLoggingMiddleware(logger, errLogger log.Logger, opts ...Option) endpoint.Middleware {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 			defer func(begin time.Time) {
-				kvs := makeKeyvals(request, response, time.Since(begin), err)
+				if err == nil && o.sampler != nil && !o.sampler.Sample(ctx, err) {
+					return
+				}
+				kvs := makeKeyvals(ctx, request, response, time.Since(begin), err, o.ctxKeyvalsers, o.prefixKeyvals, o.suffixKeyvals)
+				if o.keyvalFilter != nil && !o.keyvalFilter(kvs) {
+					return
+				}
+				dest := logger
 				if err != nil {
-					errLogger.Log(kvs...)
+					dest = errLogger
+				}
+				if o.levelFunc != nil {
+					if l := o.levelFunc(ctx, request, response, err); l != nil {
+						dest = l
+					}
+				}
+				dest.Log(kvs...)
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+}
+
+// LoggingMiddlewareLogr returns an endpoint middleware equivalent to
+// NewLoggingMiddleware, but backed by a github.com/go-logr/logr.Logger
+// instead of a go-kit/log.Logger. This lets consumers in the
+// Kubernetes/klog ecosystem plug this middleware into their existing
+// logging pipeline without adopting go-kit/log.
+//
+// The infoLogr will be used when the resulting error is nil, otherwise
+// errLogr will be used. opts accepts the same WithContextKeyvals,
+// WithSampler, WithPrefixKeyvals/WithSuffixKeyvals, and WithKeyvalFilter
+// options as NewLoggingMiddleware. WithLevelFunc is a go-kit/log.Logger
+// option - since logr has no equivalent notion of routing to an arbitrary
+// leveled log.Logger, it is accepted but ignored here.
+func LoggingMiddlewareLogr(infoLogr, errLogr logr.Logger, opts ...Option) endpoint.Middleware {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			defer func(begin time.Time) {
+				if err == nil && o.sampler != nil && !o.sampler.Sample(ctx, err) {
+					return
+				}
+				kvs := makeKeyvals(ctx, request, response, time.Since(begin), err, o.ctxKeyvalsers, o.prefixKeyvals, o.suffixKeyvals)
+				if o.keyvalFilter != nil && !o.keyvalFilter(kvs) {
+					return
+				}
+				// kvs is spread with "..." below rather than passed as a
+				// single []interface{} argument, otherwise logr would log
+				// it as one opaque value instead of flattened keysAndValues.
+				if err != nil {
+					errLogr.Error(err, "endpoint", kvs...)
 				} else {
-					logger.Log(kvs...)
+					infoLogr.Info("endpoint", kvs...)
 				}
 			}(time.Now())
 			return next(ctx, request)
@@ -59,17 +127,25 @@ func LoggingMiddleware(logger, errLogger log.Logger) endpoint.Middleware {
 
 // makeKeyvals will place the received parameters into an []interface{} to be
 // returned in the order:
-// 	1. err
-//	2. d
-//	3. req (if AppendKeyvalser is implemented)
-//	4. resp (if AppendKeyvalser is implemented)
-func makeKeyvals(req, resp interface{}, d time.Duration, err error) []interface{} {
-	KVs := []interface{}{transErrKey, err, tookKey, d}
+// 	1. prefixKeyvals
+//	2. err
+//	3. d
+//	4. req (if AppendKeyvalser is implemented)
+//	5. resp (if AppendKeyvalser is implemented)
+//	6. ctx (via each of ctxKeyvalsers, in order)
+//	7. suffixKeyvals
+func makeKeyvals(ctx context.Context, req, resp interface{}, d time.Duration, err error, ctxKeyvalsers []CtxKeyvalser, prefixKeyvals, suffixKeyvals []interface{}) []interface{} {
+	KVs := append([]interface{}{}, prefixKeyvals...)
+	KVs = append(KVs, transErrKey, err, tookKey, d)
 	if l, ok := req.(AppendKeyvalser); ok {
 		KVs = l.AppendKeyvals(KVs)
 	}
 	if l, ok := resp.(AppendKeyvalser); ok {
 		KVs = l.AppendKeyvals(KVs)
 	}
+	for _, fn := range ctxKeyvalsers {
+		KVs = fn(ctx, KVs)
+	}
+	KVs = append(KVs, suffixKeyvals...)
 	return KVs
 }
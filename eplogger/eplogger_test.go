@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/go-logr/logr"
 	"testing"
 	"time"
 )
@@ -212,6 +213,387 @@ func TestLoggingMiddleware(t *testing.T) {
 	}
 }
 
+// fakeLogSink is a logr.LogSink that captures the flattened keysAndValues
+// passed to Info/Error so tests can assert on their order.
+type fakeLogSink struct {
+	infoCalled  bool
+	errCalled   bool
+	msg         string
+	err         error
+	keysAndVals []interface{}
+}
+
+func (f *fakeLogSink) Init(info logr.RuntimeInfo) {}
+
+func (f *fakeLogSink) Enabled(level int) bool { return true }
+
+func (f *fakeLogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	f.infoCalled = true
+	f.msg = msg
+	f.keysAndVals = keysAndValues
+}
+
+func (f *fakeLogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	f.errCalled = true
+	f.msg = msg
+	f.err = err
+	f.keysAndVals = keysAndValues
+}
+
+func (f *fakeLogSink) WithValues(keysAndValues ...interface{}) logr.LogSink { return f }
+
+func (f *fakeLogSink) WithName(name string) logr.LogSink { return f }
+
+// TestNewLoggingMiddlewareWithLevelFunc tests that a configured LevelFunc
+// overrides the default err-to-level routing, and that a nil return falls
+// back to it.
+func TestNewLoggingMiddlewareWithLevelFunc(t *testing.T) {
+	var canceled = context.Canceled
+
+	var tests = map[string]struct {
+		inRespErr   error
+		expectLevel level.Value
+	}{
+		"matched error routes to info": {
+			inRespErr:   canceled,
+			expectLevel: level.InfoValue(),
+		},
+		"unmatched error falls back to errLogger": {
+			inRespErr:   errors.New("boom"),
+			expectLevel: level.ErrorValue(),
+		},
+		"nil error falls back to logger": {
+			inRespErr:   nil,
+			expectLevel: level.InfoValue(),
+		},
+	}
+
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var output []interface{}
+			logger := log.Logger(log.LoggerFunc(func(keyvals ...interface{}) error {
+				output = keyvals
+				return nil
+			}))
+			errLogger := level.Error(logger)
+			logger = level.Info(logger)
+
+			lf := LevelFuncByErrorMatchers(ErrorMatcher{
+				Match:  func(err error) bool { return err == canceled },
+				Logger: logger,
+			})
+
+			ep := func(ctx context.Context, request interface{}) (interface{}, error) {
+				return nil, tt.inRespErr
+			}
+
+			epWithMw := NewLoggingMiddleware(logger, errLogger, WithLevelFunc(lf))(ep)
+			epWithMw(context.Background(), "req")
+
+			if want, have := tt.expectLevel, output[1]; want != have {
+				t.Errorf("output[1]: want %s, have %s", want, have)
+			}
+		})
+	}
+}
+
+// TestNewLoggingMiddlewareWithSampler tests that a configured Sampler can
+// suppress logging of successful calls, but never suppresses a call that
+// returned a non-nil error.
+func TestNewLoggingMiddlewareWithSampler(t *testing.T) {
+	var tests = map[string]struct {
+		sampler   Sampler
+		inRespErr error
+		expectLog bool
+	}{
+		"sampler declines, nil error": {
+			sampler:   NewRateSampler(2),
+			inRespErr: nil,
+			expectLog: false,
+		},
+		"sampler declines, non-nil error always logs": {
+			sampler:   NewRateSampler(2),
+			inRespErr: errors.New("boom"),
+			expectLog: true,
+		},
+		"no sampler always logs": {
+			sampler:   nil,
+			inRespErr: nil,
+			expectLog: true,
+		},
+	}
+
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			logCalled := false
+			logger := log.Logger(log.LoggerFunc(func(keyvals ...interface{}) error {
+				logCalled = true
+				return nil
+			}))
+			errLogger := level.Error(logger)
+			logger = level.Info(logger)
+
+			ep := func(ctx context.Context, request interface{}) (interface{}, error) {
+				return nil, tt.inRespErr
+			}
+
+			var opts []Option
+			if tt.sampler != nil {
+				opts = append(opts, WithSampler(tt.sampler))
+			}
+			epWithMw := NewLoggingMiddleware(logger, errLogger, opts...)(ep)
+			epWithMw(context.Background(), "req")
+
+			if want, have := tt.expectLog, logCalled; want != have {
+				t.Errorf("Log called: want %v, have %v", want, have)
+			}
+		})
+	}
+}
+
+// TestNewTokenBucketSampler tests that the token bucket sampler allows up
+// to burst successful calls and always allows calls with a non-nil error.
+func TestNewTokenBucketSampler(t *testing.T) {
+	s := NewTokenBucketSampler(0, 1)
+
+	if !s.Sample(context.Background(), nil) {
+		t.Errorf("expected first call within burst to be sampled")
+	}
+	if s.Sample(context.Background(), nil) {
+		t.Errorf("expected call beyond burst to be declined")
+	}
+	if !s.Sample(context.Background(), errors.New("boom")) {
+		t.Errorf("expected error call to always be sampled")
+	}
+}
+
+// TestNewLoggingMiddlewareWithPrefixSuffixKeyvals tests that
+// WithPrefixKeyvals and WithSuffixKeyvals place their keyvals at the front
+// and back of the logged line, respectively, surrounding the usual
+// transport_error/took/req/resp/ctx keyvals.
+func TestNewLoggingMiddlewareWithPrefixSuffixKeyvals(t *testing.T) {
+	var output []interface{}
+	logger := log.Logger(log.LoggerFunc(func(keyvals ...interface{}) error {
+		output = keyvals
+		return nil
+	}))
+	errLogger := level.Error(logger)
+	logger = level.Info(logger)
+
+	ep := func(ctx context.Context, request interface{}) (interface{}, error) {
+		return nil, nil
+	}
+
+	epWithMw := NewLoggingMiddleware(logger, errLogger,
+		WithPrefixKeyvals("environment", "prod"),
+		WithSuffixKeyvals("caller", "eplogger"),
+	)(ep)
+	epWithMw(context.Background(), "req")
+
+	// output[0:2] is "level"/value, prepended by the level.Info wrapper.
+	if want, have := "environment", output[2]; want != have {
+		t.Errorf("output[2]: want %s, have %s", want, have)
+	}
+	if want, have := "prod", output[3]; want != have {
+		t.Errorf("output[3]: want %s, have %s", want, have)
+	}
+	if want, have := transErrKey, output[4]; want != have {
+		t.Errorf("output[4]: want %s, have %s", want, have)
+	}
+	if want, have := "caller", output[len(output)-2]; want != have {
+		t.Errorf("output[len-2]: want %s, have %s", want, have)
+	}
+	if want, have := "eplogger", output[len(output)-1]; want != have {
+		t.Errorf("output[len-1]: want %s, have %s", want, have)
+	}
+}
+
+// TestNewLoggingMiddlewareWithKeyvalFilter tests that a configured
+// KeyvalFilter can drop a log line based on the composed keyvals, and that
+// a filtered call produces zero Log invocations.
+func TestNewLoggingMiddlewareWithKeyvalFilter(t *testing.T) {
+	var tests = map[string]struct {
+		filter    KeyvalFilter
+		req       interface{}
+		expectLog bool
+	}{
+		"AllowByKey allows matching tenant": {
+			filter:    AllowByKey(stringFieldKey, "acme"),
+			req:       AppendKeyvalserTest{StringField: "acme"},
+			expectLog: true,
+		},
+		"AllowByKey drops non-matching tenant": {
+			filter:    AllowByKey(stringFieldKey, "acme"),
+			req:       AppendKeyvalserTest{StringField: "other"},
+			expectLog: false,
+		},
+		"DenyByKey drops health checks": {
+			filter:    DenyByKey(stringFieldKey, "health"),
+			req:       AppendKeyvalserTest{StringField: "health"},
+			expectLog: false,
+		},
+		"DenyByKey allows non-matching method": {
+			filter:    DenyByKey(stringFieldKey, "health"),
+			req:       AppendKeyvalserTest{StringField: "other"},
+			expectLog: true,
+		},
+	}
+
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			logCalled := false
+			logger := log.Logger(log.LoggerFunc(func(keyvals ...interface{}) error {
+				logCalled = true
+				return nil
+			}))
+			errLogger := level.Error(logger)
+			logger = level.Info(logger)
+
+			ep := func(ctx context.Context, request interface{}) (interface{}, error) {
+				return nil, nil
+			}
+
+			epWithMw := NewLoggingMiddleware(logger, errLogger, WithKeyvalFilter(tt.filter))(ep)
+			epWithMw(context.Background(), tt.req)
+
+			if want, have := tt.expectLog, logCalled; want != have {
+				t.Errorf("Log called: want %v, have %v", want, have)
+			}
+		})
+	}
+}
+
+// TestNewLoggingMiddlewareWithKeyvalFilterAllocs tests that a filtered-out
+// call (the KeyvalFilter declines and Log is never invoked) allocates no
+// more than building the keyvals via makeKeyvals alone would.
+func TestNewLoggingMiddlewareWithKeyvalFilterAllocs(t *testing.T) {
+	logger := log.NewNopLogger()
+	denyAll := func(keyvals []interface{}) bool { return false }
+
+	ep := func(ctx context.Context, request interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	epWithMw := NewLoggingMiddleware(logger, logger, WithKeyvalFilter(denyAll))(ep)
+
+	req := AppendKeyvalserTest{StringField: "dropped"}
+
+	middlewareAllocs := testing.AllocsPerRun(100, func() {
+		epWithMw(context.Background(), req)
+	})
+	baselineAllocs := testing.AllocsPerRun(100, func() {
+		makeKeyvals(context.Background(), req, nil, 0, nil, nil, nil, nil)
+	})
+
+	if middlewareAllocs > baselineAllocs {
+		t.Errorf("filtered call allocated more than makeKeyvals alone: middleware=%v, makeKeyvals=%v", middlewareAllocs, baselineAllocs)
+	}
+}
+
+type ctxKey string
+
+const testCtxKey ctxKey = "tenant"
+
+// TestNewLoggingMiddlewareWithContextKeyvals tests that CtxKeyvalser funcs
+// registered via WithContextKeyvals contribute key/value pairs extracted
+// from the request's context.Context.
+func TestNewLoggingMiddlewareWithContextKeyvals(t *testing.T) {
+	var output []interface{}
+	logger := log.Logger(log.LoggerFunc(func(keyvals ...interface{}) error {
+		output = keyvals
+		return nil
+	}))
+	errLogger := level.Error(logger)
+	logger = level.Info(logger)
+
+	ep := func(ctx context.Context, request interface{}) (interface{}, error) {
+		return nil, nil
+	}
+
+	epWithMw := NewLoggingMiddleware(logger, errLogger, WithContextKeyvals(CtxKeyvalsFromKeys(testCtxKey)))(ep)
+
+	ctx := context.WithValue(context.Background(), testCtxKey, "acme")
+	epWithMw(ctx, "req")
+
+	if want, have := 8, len(output); want != have {
+		t.Fatalf("len of output is different than expected: want %d, have %d", want, have)
+	}
+	if want, have := "tenant", output[6]; want != have {
+		t.Errorf("output[6]: want %s, have %s", want, have)
+	}
+	if want, have := "acme", output[7]; want != have {
+		t.Errorf("output[7]: want %s, have %s", want, have)
+	}
+}
+
+// TestLoggingMiddlewareLogr tests that LoggingMiddlewareLogr calls the
+// underlying endpoint and logs the flattened keyvals produced by
+// makeKeyvals through a logr.Logger, without double-wrapping them into
+// a single []interface{} value.
+func TestLoggingMiddlewareLogr(t *testing.T) {
+	var tests = map[string]struct {
+		inRespErr error
+	}{
+		"nil error":     {inRespErr: nil},
+		"non-nil error": {inRespErr: errors.New("an error")},
+	}
+
+	for testName, tt := range tests {
+		t.Run(testName, func(t *testing.T) {
+			sink := &fakeLogSink{}
+			l := logr.New(sink)
+
+			req := AppendKeyvalserTest{StringField: "req string"}
+			resp := AppendKeyvalserTest{StringField: "resp string"}
+
+			endpointExecuted := false
+			ep := func(ctx context.Context, request interface{}) (interface{}, error) {
+				endpointExecuted = true
+				return resp, tt.inRespErr
+			}
+
+			epWithMw := LoggingMiddlewareLogr(l, l)(ep)
+			epWithMw(context.Background(), req)
+
+			if !endpointExecuted {
+				t.Errorf("endpoint was never executed")
+			}
+
+			if tt.inRespErr != nil {
+				if !sink.errCalled {
+					t.Errorf("expected Error to be called")
+				}
+				if sink.err != tt.inRespErr {
+					t.Errorf("err: want %v, have %v", tt.inRespErr, sink.err)
+				}
+			} else if !sink.infoCalled {
+				t.Errorf("expected Info to be called")
+			}
+
+			if want, have := 8, len(sink.keysAndVals); want != have {
+				t.Fatalf("len of keysAndVals is different than expected: want %d, have %d", want, have)
+			}
+			if want, have := transErrKey, sink.keysAndVals[0]; want != have {
+				t.Errorf("keysAndVals[0]: want %s, have %s", want, have)
+			}
+			if want, have := tookKey, sink.keysAndVals[2]; want != have {
+				t.Errorf("keysAndVals[2]: want %s, have %s", want, have)
+			}
+			if want, have := stringFieldKey, sink.keysAndVals[4]; want != have {
+				t.Errorf("keysAndVals[4]: want %s, have %s", want, have)
+			}
+			if want, have := req.StringField, sink.keysAndVals[5]; want != have {
+				t.Errorf("keysAndVals[5]: want %s, have %s", want, have)
+			}
+			if want, have := stringFieldKey, sink.keysAndVals[6]; want != have {
+				t.Errorf("keysAndVals[6]: want %s, have %s", want, have)
+			}
+			if want, have := resp.StringField, sink.keysAndVals[7]; want != have {
+				t.Errorf("keysAndVals[7]: want %s, have %s", want, have)
+			}
+		})
+	}
+}
+
 // BenchmarkLoggingMiddlewareWithErr tests how long the middleware takes to execute when
 // the resulting err is not nil.
 // The benchmark output by BenchmarkLoggingMiddlewareCreation should be subtracted from the
@@ -334,6 +716,28 @@ func BenchmarkMakeKeyvals(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		makeKeyvals(req, resp, d, err)
+		makeKeyvals(context.Background(), req, resp, d, err, nil, nil, nil)
+	}
+}
+
+// BenchmarkMakeKeyvalsWithPrefixSuffix tests how much extra allocation
+// cost 4 prefix + 4 suffix keyvals add over BenchmarkMakeKeyvals, which
+// should be a single extra slice grow.
+func BenchmarkMakeKeyvalsWithPrefixSuffix(b *testing.B) {
+	req := AppendKeyvalserTest{
+		StringField: "req string",
+	}
+	resp := AppendKeyvalserTest{
+		StringField: "resp string",
+	}
+	d := time.Duration(1)
+	err := errors.New("test")
+	prefix := []interface{}{"ts", "now", "level", "info"}
+	suffix := []interface{}{"caller", "eplogger", "environment", "prod"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		makeKeyvals(context.Background(), req, resp, d, err, nil, prefix, suffix)
 	}
 }
@@ -0,0 +1,67 @@
+package eplogger
+
+// KeyvalFilter is a predicate run against the fully composed keyvals for an
+// invocation (transport_error, took, and any req/resp/ctx/prefix/suffix
+// keyvals). Returning false drops the log line entirely.
+type KeyvalFilter func(keyvals []interface{}) bool
+
+// WithKeyvalFilter drops an entire log line whose composed keyvals do not
+// satisfy pred, e.g. to only log requests for a given tenant, or to drop
+// health-check endpoints by inspecting a "method" keyval a request's
+// AppendKeyvals contributed. pred runs after makeKeyvals, so it can filter
+// on fields only the request/response types know about.
+func WithKeyvalFilter(pred KeyvalFilter) Option {
+	return func(o *options) {
+		o.keyvalFilter = pred
+	}
+}
+
+// AllowByKey returns a KeyvalFilter that passes only keyvals containing
+// key with a value equal to one of allowed. The values must be of a
+// comparable type (e.g. string, int); comparing an uncomparable type
+// (slice, map, func) panics.
+func AllowByKey(key string, allowed ...interface{}) KeyvalFilter {
+	return func(keyvals []interface{}) bool {
+		v, ok := lookupKeyval(keyvals, key)
+		if !ok {
+			return false
+		}
+		for _, a := range allowed {
+			if v == a {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// DenyByKey returns a KeyvalFilter that drops keyvals containing key with
+// a value equal to one of denied. Keyvals missing key, or whose value for
+// key is not in denied, are passed. The values must be of a comparable
+// type (e.g. string, int); comparing an uncomparable type (slice, map,
+// func) panics.
+func DenyByKey(key string, denied ...interface{}) KeyvalFilter {
+	return func(keyvals []interface{}) bool {
+		v, ok := lookupKeyval(keyvals, key)
+		if !ok {
+			return true
+		}
+		for _, d := range denied {
+			if v == d {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// lookupKeyval scans keyvals (alternating key, value, key, value, ...) for
+// key and returns its value.
+func lookupKeyval(keyvals []interface{}, key string) (interface{}, bool) {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if k, ok := keyvals[i].(string); ok && k == key {
+			return keyvals[i+1], true
+		}
+	}
+	return nil, false
+}
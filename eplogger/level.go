@@ -0,0 +1,52 @@
+package eplogger
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+)
+
+// LevelFunc selects which log.Logger a given invocation should be logged
+// to, based on the request, response, and resulting err. It lets callers
+// route e.g. context.Canceled to an Info/Debug logger, validation errors
+// to a Warn logger, and reserve Error for true faults.
+//
+// Returning nil falls back to the middleware's default behavior (errLogger
+// when err is non-nil, logger otherwise).
+type LevelFunc func(ctx context.Context, req, resp interface{}, err error) log.Logger
+
+// ErrorMatcher pairs a predicate with the log.Logger invocations matching
+// it should be logged to. Matchers are checked in order by
+// LevelFuncByErrorMatchers.
+type ErrorMatcher struct {
+	Match  func(error) bool
+	Logger log.Logger
+}
+
+// WithLevelFunc overrides the default err-to-level routing (non-nil err ->
+// errLogger, nil err -> logger) with fn. fn is only consulted; returning
+// nil from it falls back to the default routing.
+func WithLevelFunc(fn LevelFunc) Option {
+	return func(o *options) {
+		o.levelFunc = fn
+	}
+}
+
+// LevelFuncByErrorMatchers returns a LevelFunc that checks matchers in
+// order and returns the Logger of the first one whose Match(err) is true,
+// inspired by Tendermint's filter-by-key log package. If err is nil, or no
+// matcher matches, it returns nil so the middleware's default routing
+// applies.
+func LevelFuncByErrorMatchers(matchers ...ErrorMatcher) LevelFunc {
+	return func(ctx context.Context, req, resp interface{}, err error) log.Logger {
+		if err == nil {
+			return nil
+		}
+		for _, m := range matchers {
+			if m.Match(err) {
+				return m.Logger
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,44 @@
+package eplogger
+
+// options holds the optional behavior configured on a LoggingMiddleware
+// via the Option funcs below. The zero value matches the long-standing
+// behavior of LoggingMiddleware.
+type options struct {
+	ctxKeyvalsers []CtxKeyvalser
+	levelFunc     LevelFunc
+	sampler       Sampler
+	prefixKeyvals []interface{}
+	suffixKeyvals []interface{}
+	keyvalFilter  KeyvalFilter
+}
+
+// Option configures optional behavior of NewLoggingMiddleware.
+type Option func(*options)
+
+// WithContextKeyvals registers one or more CtxKeyvalser funcs that will be
+// consulted, in order, to pull additional key/value pairs out of the
+// request's context.Context before logging (e.g. request_id, trace_id,
+// span_id, tenant, user).
+func WithContextKeyvals(fns ...CtxKeyvalser) Option {
+	return func(o *options) {
+		o.ctxKeyvalsers = append(o.ctxKeyvalsers, fns...)
+	}
+}
+
+// WithPrefixKeyvals places kvs at the very front of every logged keyvals
+// slice, ahead of transport_error and took. Useful for fields operators
+// expect to lead a logfmt line, e.g. ts, level, msg.
+func WithPrefixKeyvals(kvs ...interface{}) Option {
+	return func(o *options) {
+		o.prefixKeyvals = append(o.prefixKeyvals, kvs...)
+	}
+}
+
+// WithSuffixKeyvals places kvs at the very end of every logged keyvals
+// slice, after any context-derived keyvals. Useful for fields operators
+// expect to trail a logfmt line, e.g. caller, environment.
+func WithSuffixKeyvals(kvs ...interface{}) Option {
+	return func(o *options) {
+		o.suffixKeyvals = append(o.suffixKeyvals, kvs...)
+	}
+}
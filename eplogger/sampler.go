@@ -0,0 +1,67 @@
+package eplogger
+
+import (
+	"context"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
+)
+
+// Sampler decides whether a successful invocation should be logged. The
+// middleware only calls Sample when err is nil; a non-nil err is always
+// logged without consulting the Sampler, so implementations do not need
+// to inspect err themselves.
+type Sampler interface {
+	Sample(ctx context.Context, err error) bool
+}
+
+// WithSampler configures s to decide whether successful invocations should
+// be logged. Errors are always logged. With no sampler configured, every
+// invocation is logged (the long-standing default behavior).
+func WithSampler(s Sampler) Option {
+	return func(o *options) {
+		o.sampler = s
+	}
+}
+
+// rateSampler is a Sampler that logs 1 in every n successful calls.
+type rateSampler struct {
+	n       int64
+	counter int64
+}
+
+// NewRateSampler returns a Sampler that logs 1 in every n successful
+// calls. Errors are always logged. n <= 1 logs every call.
+func NewRateSampler(n int) Sampler {
+	return &rateSampler{n: int64(n)}
+}
+
+func (s *rateSampler) Sample(ctx context.Context, err error) bool {
+	if err != nil {
+		return true
+	}
+	if s.n <= 1 {
+		return true
+	}
+	return atomic.AddInt64(&s.counter, 1)%s.n == 0
+}
+
+// tokenBucketSampler is a Sampler that logs successful calls at no more
+// than r events/sec, with burst allowed to momentarily exceed that rate.
+type tokenBucketSampler struct {
+	limiter *rate.Limiter
+}
+
+// NewTokenBucketSampler returns a Sampler that logs successful calls at no
+// more than r events/sec, using a token bucket of size burst. Errors are
+// always logged.
+func NewTokenBucketSampler(r float64, burst int) Sampler {
+	return &tokenBucketSampler{limiter: rate.NewLimiter(rate.Limit(r), burst)}
+}
+
+func (s *tokenBucketSampler) Sample(ctx context.Context, err error) bool {
+	if err != nil {
+		return true
+	}
+	return s.limiter.Allow()
+}